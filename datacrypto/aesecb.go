@@ -3,7 +3,6 @@ package datacrypto
 import (
 	"crypto/aes"
 	"errors"
-	"reflect"
 )
 
 // aesECBSecureData implements Crypto interface using AES ECB to encrypt and decrypt data
@@ -39,131 +38,19 @@ func (sd aesECBSecureData) Decrypt(encryptedText string) (string, error) {
 }
 
 // EncryptStruct crawls all anottated struct properties and encrypts them in place
-func (sd aesECBSecureData) EncryptStruct(instance interface{}) (retVal interface{}, err error) {
-	instanceType := reflect.TypeOf(instance)
-	if instanceType.Kind() != reflect.Ptr {
-		return nil, errors.New("must receive a pointer, but received " + instanceType.Kind().String())
-	}
-
-	instanceType = instanceType.Elem()
-	if instanceType.Kind() != reflect.Struct {
-		return nil, errors.New("must receive a pointer to a struct, but received " + instanceType.Kind().String())
-	}
-
-	instanceValue := reflect.ValueOf(instance).Elem()
-
-	for i := 0; i < instanceType.NumField(); i++ {
-		currentFieldTag := instanceType.Field(i).Tag
-		cryptValue, hasCryptTag := currentFieldTag.Lookup("crypt")
-		field := instanceValue.Field(i)
-
-		if field.IsValid() && field.CanSet() {
-			switch field.Kind() {
-			case reflect.String:
-				if hasCryptTag && cryptValue == "true" {
-					encryptedFieldValue, err := sd.Encrypt(field.String())
-					if err != nil {
-						return nil, err
-					}
-					field.SetString(encryptedFieldValue)
-				}
-
-			case reflect.Ptr:
-				if !field.IsNil() && field.Elem().IsValid() {
-					switch field.Elem().Kind() {
-					case reflect.String:
-						if hasCryptTag && cryptValue == "true" {
-							encryptedFieldValue, err := sd.Encrypt(field.Elem().String())
-							if err != nil {
-								return nil, err
-							}
-							field.Elem().SetString(encryptedFieldValue)
-						}
-					case reflect.Struct:
-						_, err := sd.EncryptStruct(field.Interface())
-						if err != nil {
-							return nil, err
-						}
-					}
-				}
-			case reflect.Struct:
-				_, err = sd.EncryptStruct(field.Addr().Interface())
-				if err != nil {
-					return nil, err
-				}
-			default:
-				if hasCryptTag && cryptValue == "true" {
-					return nil, errors.New("Field must be a string or a pointer to a string to be decrypted")
-				}
-			}
-		}
-	}
-
-	return instance, nil
+func (sd aesECBSecureData) EncryptStruct(instance interface{}) (interface{}, error) {
+	return encryptStructWith(sd, instance)
 }
 
 // DecryptStruct crawls all anottated struct properties and deecrypts them in place
 func (sd aesECBSecureData) DecryptStruct(encryptedInstance interface{}) (interface{}, error) {
-	instanceType := reflect.TypeOf(encryptedInstance)
-	if instanceType.Kind() != reflect.Ptr {
-		return nil, errors.New("must receive a pointer, but received " + instanceType.Kind().String())
-	}
-
-	instanceType = instanceType.Elem()
-	if instanceType.Kind() != reflect.Struct {
-		return nil, errors.New("must receive a pointer to a struct, but received " + instanceType.Kind().String())
-	}
-
-	instanceValue := reflect.ValueOf(encryptedInstance).Elem()
-
-	for i := 0; i < instanceType.NumField(); i++ {
-		currentFieldTag := instanceType.Field(i).Tag
-		cryptValue, hasCryptTag := currentFieldTag.Lookup("crypt")
-		field := instanceValue.Field(i)
-
-		if field.IsValid() && field.CanSet() {
-			switch field.Kind() {
-			case reflect.String:
-				if hasCryptTag && cryptValue == "true" {
-					decryptedFieldValue, err := sd.Decrypt(field.String())
-					if err != nil {
-						return nil, err
-					}
-					field.SetString(decryptedFieldValue)
-				}
-
-			case reflect.Ptr:
-				if !field.IsNil() && field.Elem().IsValid() {
-					switch field.Elem().Kind() {
-					case reflect.String:
-						if hasCryptTag && cryptValue == "true" {
-							decryptedFieldValue, err := sd.Decrypt(field.Elem().String())
-							if err != nil {
-								return nil, err
-							}
-							field.Elem().SetString(decryptedFieldValue)
-						}
-					case reflect.Struct:
-						_, err := sd.DecryptStruct(field.Interface())
-						if err != nil {
-							return nil, err
-						}
-					}
-				}
-			case reflect.Struct:
-				_, err := sd.DecryptStruct(field.Addr().Interface())
-				if err != nil {
-					return nil, err
-				}
-			default:
-				if hasCryptTag && cryptValue == "true" {
-					return nil, errors.New("Field must be a string or a pointer to a string to be decrypted")
-				}
-			}
-		}
-	}
+	return decryptStructWith(sd, encryptedInstance)
+}
 
-	return encryptedInstance, nil
+// Hash implements Hasher using a keyed HMAC, so the digest cannot be
+// recomputed without this Crypto's key.
+func (sd aesECBSecureData) Hash(algo string, text string) (string, error) {
+	return hmacHash(sd.key, algo, text)
 }
 
 // NewAESECB returns a new Crypto using AES ECB
@@ -33,6 +33,20 @@ type testRecursiveStruct struct {
 	Field3 TestStruct
 }
 
+type testDualPointerStruct struct {
+	Field1 *string `crypt:"true"`
+	Field2 *string `crypt:"true"`
+}
+
+type testSliceOfStringsStruct struct {
+	Strings []string `crypt:"true"`
+}
+
+type testStructWithUnexportedField struct {
+	Name   string `crypt:"true"`
+	secret string
+}
+
 type testRecursiveStructPointer struct {
 	Field1 string `crypt:"true"`
 	Field2 *testRecursiveStruct
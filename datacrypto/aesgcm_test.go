@@ -0,0 +1,182 @@
+package datacrypto
+
+import (
+	"errors"
+	"testing"
+)
+
+const gcmTestKey = "test-gcm-key"
+
+func Test_aesGCMSecureData_EncryptDecrypt_roundtrip(t *testing.T) {
+	sd := NewAES256GCM(gcmTestKey)
+
+	encrypted, err := sd.Encrypt(value1)
+	if err != nil {
+		t.Fatalf("unexpected error encrypting: %v", err)
+	}
+
+	if encrypted == value1 {
+		t.Fatalf("expected ciphertext to differ from plaintext")
+	}
+
+	decrypted, err := sd.Decrypt(encrypted)
+	if err != nil {
+		t.Fatalf("unexpected error decrypting: %v", err)
+	}
+
+	if decrypted != value1 {
+		t.Fatalf("got %q, want %q", decrypted, value1)
+	}
+}
+
+func Test_aesGCMSecureData_Encrypt_isNonDeterministic(t *testing.T) {
+	sd := NewAES128GCM(gcmTestKey)
+
+	first, err := sd.Encrypt(value1)
+	if err != nil {
+		t.Fatalf("unexpected error encrypting: %v", err)
+	}
+
+	second, err := sd.Encrypt(value1)
+	if err != nil {
+		t.Fatalf("unexpected error encrypting: %v", err)
+	}
+
+	if first == second {
+		t.Fatalf("expected two encryptions of the same plaintext to differ due to the random nonce")
+	}
+}
+
+func Test_aesGCMSecureData_Decrypt_rejectsTamperedCiphertext(t *testing.T) {
+	sd := NewAES128GCM(gcmTestKey)
+
+	encrypted, err := sd.Encrypt(value1)
+	if err != nil {
+		t.Fatalf("unexpected error encrypting: %v", err)
+	}
+
+	tampered := []byte(encrypted)
+	tampered[len(tampered)-1] ^= 0xff
+
+	if _, err := sd.Decrypt(string(tampered)); !errors.Is(err, ErrAuthenticationFailed) {
+		t.Fatalf("got error %v, want %v", err, ErrAuthenticationFailed)
+	}
+}
+
+func Test_aesGCMSecureData_DecryptStruct_doesNotPartiallyMutateOnError(t *testing.T) {
+	sd := NewAES128GCM(gcmTestKey)
+
+	encrypted, err := sd.Encrypt(value1)
+	if err != nil {
+		t.Fatalf("unexpected error encrypting: %v", err)
+	}
+
+	tamperedField3 := []byte(encrypted)
+	tamperedField3[len(tamperedField3)-1] ^= 0xff
+
+	instance := &TestStruct{
+		Field1: encrypted,
+		Field2: value2,
+		Field3: string(tamperedField3),
+	}
+
+	original := *instance
+
+	if _, err := sd.DecryptStruct(instance); !errors.Is(err, ErrAuthenticationFailed) {
+		t.Fatalf("got error %v, want %v", err, ErrAuthenticationFailed)
+	}
+
+	if *instance != original {
+		t.Fatalf("expected instance to be left untouched on error, got %+v", instance)
+	}
+}
+
+// Test_aesGCMSecureData_DecryptStruct_doesNotPartiallyMutateOnError_pointerFields
+// guards against a deep-copy regression: DecryptStruct decrypts into a scratch
+// copy of the instance, but a shallow struct copy still aliases pointer
+// fields, so decrypting the copy would decrypt the original's pointee too.
+func Test_aesGCMSecureData_DecryptStruct_doesNotPartiallyMutateOnError_pointerFields(t *testing.T) {
+	sd := NewAES128GCM(gcmTestKey)
+
+	encryptedField1, err := sd.Encrypt(value1)
+	if err != nil {
+		t.Fatalf("unexpected error encrypting: %v", err)
+	}
+	encryptedField2, err := sd.Encrypt(value2)
+	if err != nil {
+		t.Fatalf("unexpected error encrypting: %v", err)
+	}
+
+	tampered := []byte(encryptedField2)
+	tampered[len(tampered)-1] ^= 0xff
+
+	field1, field2 := encryptedField1, string(tampered)
+	instance := &testDualPointerStruct{Field1: &field1, Field2: &field2}
+
+	if _, err := sd.DecryptStruct(instance); !errors.Is(err, ErrAuthenticationFailed) {
+		t.Fatalf("got error %v, want %v", err, ErrAuthenticationFailed)
+	}
+
+	if *instance.Field1 != encryptedField1 {
+		t.Fatalf("expected Field1's pointee to be left untouched on error, got %q", *instance.Field1)
+	}
+}
+
+// Test_aesGCMSecureData_DecryptStruct_doesNotPartiallyMutateOnError_sliceFields
+// guards against the same deep-copy regression for slices: a shallow struct
+// copy still shares the original slice's backing array.
+func Test_aesGCMSecureData_DecryptStruct_doesNotPartiallyMutateOnError_sliceFields(t *testing.T) {
+	sd := NewAES128GCM(gcmTestKey)
+
+	encryptedValue1, err := sd.Encrypt(value1)
+	if err != nil {
+		t.Fatalf("unexpected error encrypting: %v", err)
+	}
+	encryptedValue3, err := sd.Encrypt(value3)
+	if err != nil {
+		t.Fatalf("unexpected error encrypting: %v", err)
+	}
+
+	tampered := []byte(encryptedValue3)
+	tampered[len(tampered)-1] ^= 0xff
+
+	instance := &testSliceOfStringsStruct{Strings: []string{encryptedValue1, string(tampered)}}
+
+	if _, err := sd.DecryptStruct(instance); !errors.Is(err, ErrAuthenticationFailed) {
+		t.Fatalf("got error %v, want %v", err, ErrAuthenticationFailed)
+	}
+
+	if instance.Strings[0] != encryptedValue1 {
+		t.Fatalf("expected element 0 to be left untouched on error, got %q", instance.Strings[0])
+	}
+}
+
+func Test_aesGCMSecureData_EncryptStruct_DecryptStruct_roundtrip(t *testing.T) {
+	sd := NewAES256GCM(gcmTestKey)
+
+	instance := &TestStruct{
+		Field1: value1,
+		Field2: value2,
+		Field3: value3,
+	}
+
+	if _, err := sd.EncryptStruct(instance); err != nil {
+		t.Fatalf("unexpected error encrypting struct: %v", err)
+	}
+
+	if instance.Field1 == value1 || instance.Field3 == value3 {
+		t.Fatalf("expected annotated fields to be encrypted")
+	}
+
+	if instance.Field2 != value2 {
+		t.Fatalf("expected unannotated field to be left untouched")
+	}
+
+	if _, err := sd.DecryptStruct(instance); err != nil {
+		t.Fatalf("unexpected error decrypting struct: %v", err)
+	}
+
+	if instance.Field1 != value1 || instance.Field2 != value2 || instance.Field3 != value3 {
+		t.Fatalf("got %+v, want round-tripped values", instance)
+	}
+}
@@ -0,0 +1,51 @@
+package datacrypto
+
+import (
+	"errors"
+	"io"
+)
+
+// ErrDeterministicUnsupported is returned when a field is tagged
+// `crypt:"true,deterministic"` but the configured Crypto does not implement
+// DeterministicEncrypter.
+var ErrDeterministicUnsupported = errors.New("datacrypto: crypto does not support deterministic encryption")
+
+// ErrHashUnsupported is returned when a field is tagged `crypt:"hash"` but
+// the configured Crypto does not implement Hasher.
+var ErrHashUnsupported = errors.New("datacrypto: crypto does not support hashing")
+
+// ErrStreamUnsupported is returned when a field is tagged `crypt:"true,stream"`
+// but the configured Crypto does not implement StreamCrypto.
+var ErrStreamUnsupported = errors.New("datacrypto: crypto does not support streaming")
+
+// DeterministicEncrypter is implemented by Crypto types that can also produce
+// a stable ciphertext for a given plaintext, so that equal plaintexts always
+// encrypt to equal ciphertexts. EncryptStruct uses this for fields tagged
+// `crypt:"true,deterministic"`, trading away semantic security for the
+// ability to index or search the encrypted column. Ciphertexts it produces
+// are decrypted with the Crypto's regular Decrypt method.
+type DeterministicEncrypter interface {
+	EncryptDeterministic(text string) (string, error)
+}
+
+// Hasher is implemented by Crypto types that can replace a field with a
+// one-way keyed digest instead of a reversible ciphertext. EncryptStruct uses
+// this for fields tagged `crypt:"hash"` or `crypt:"hash,<algo>"`;
+// DecryptStruct leaves such fields untouched, since a hash cannot be reversed.
+type Hasher interface {
+	Hash(algo string, text string) (string, error)
+}
+
+// StreamCrypto is implemented by Crypto types that can encrypt/decrypt
+// arbitrarily large data without buffering all of it in memory, by splitting
+// it into independently authenticated chunks. EncryptStruct/DecryptStruct use
+// this for fields tagged `crypt:"true,stream"`.
+type StreamCrypto interface {
+	// EncryptStream reads src to completion, writing the encrypted,
+	// chunked form of it to dst.
+	EncryptStream(dst io.Writer, src io.Reader) error
+	// DecryptStream reads src to completion, writing the decrypted
+	// plaintext of it to dst. It returns ErrAuthenticationFailed if any
+	// chunk fails to authenticate.
+	DecryptStream(dst io.Writer, src io.Reader) error
+}
@@ -0,0 +1,25 @@
+package datacrypto
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+)
+
+// ErrUnsupportedHashAlgorithm is returned when a `crypt:"hash,..."` tag names
+// an algorithm this package doesn't implement.
+var ErrUnsupportedHashAlgorithm = errors.New("datacrypto: unsupported hash algorithm")
+
+// hmacHash computes a keyed, hex-encoded HMAC digest of text, keyed so that
+// the digest cannot be recomputed by anyone without access to key.
+func hmacHash(key []byte, algo string, text string) (string, error) {
+	switch algo {
+	case "sha256", "":
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(text))
+		return hex.EncodeToString(mac.Sum(nil)), nil
+	default:
+		return "", ErrUnsupportedHashAlgorithm
+	}
+}
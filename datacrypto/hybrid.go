@@ -0,0 +1,227 @@
+package datacrypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// hybridWireVersion identifies the envelope layout produced by hybridSecureData.
+const hybridWireVersion byte = 1
+
+// hybridKeySize is the size, in bytes, of the ephemeral AES data key generated
+// for every Encrypt call.
+const hybridKeySize = 32
+
+// ErrNoPrivateKey is returned by Decrypt/DecryptStruct when the Crypto was
+// built with NewHybridRSA using a nil private key, e.g. in a service that
+// only holds the public key and is meant to encrypt, not decrypt.
+var ErrNoPrivateKey = errors.New("hybrid: no private key configured for decryption")
+
+// hybridSecureData implements Crypto interface using hybrid RSA+AES envelope
+// encryption: a fresh AES-256-GCM data key is generated for every Encrypt
+// call, the data is sealed with it, and the data key itself is sealed under
+// an RSA public key using OAEP. Only the holder of the matching private key
+// can unseal the data key and, in turn, the data. EncryptStruct shares a
+// single data key (and its one RSA-OAEP wrap) across every field in the
+// struct instead of calling Encrypt, and therefore paying for a fresh RSA
+// operation, per field - see hybridCallEncrypter.
+type hybridSecureData struct {
+	pub  *rsa.PublicKey
+	priv *rsa.PrivateKey
+}
+
+// NewHybridRSA instantiates a new Crypto that seals a per-call AES data key
+// under pub and decrypts by unsealing it with priv. priv may be nil for
+// encryption-only services that only hold the public key; such a Crypto
+// returns ErrNoPrivateKey from Decrypt/DecryptStruct.
+func NewHybridRSA(pub *rsa.PublicKey, priv *rsa.PrivateKey) Crypto {
+	return &hybridSecureData{pub: pub, priv: priv}
+}
+
+// Encrypt generates a fresh AES-256 data key, encrypts text with it under
+// AES-GCM, and seals the data key under the RSA public key with OAEP. The
+// wire format is a version byte, a 2-byte big-endian wrapped-key length, the
+// RSA-OAEP-wrapped data key, the GCM nonce, and finally the sealed
+// ciphertext (with its authentication tag appended by GCM).
+func (sd hybridSecureData) Encrypt(text string) (string, error) {
+	encrypted, err := sd.encrypt([]byte(text))
+	return string(encrypted), err
+}
+
+// Decrypt unseals the data key with the configured private key and decrypts
+// the payload with it. It returns ErrAuthenticationFailed when the AEAD tag
+// does not match, and ErrNoPrivateKey when no private key was configured.
+func (sd hybridSecureData) Decrypt(encryptedText string) (string, error) {
+	if encryptedText == "" {
+		return encryptedText, nil
+	}
+	decrypted, err := sd.decrypt([]byte(encryptedText))
+	return string(decrypted), err
+}
+
+// EncryptStruct crawls all anottated struct properties and encrypts them in
+// place. A single data key is generated and sealed under the public key once
+// for the whole call - including any nested structs the walk descends into -
+// rather than once per field, so the expensive RSA-OAEP operation is paid
+// once per EncryptStruct call; each field still gets its own fresh GCM nonce.
+func (sd hybridSecureData) EncryptStruct(instance interface{}) (interface{}, error) {
+	call, err := newHybridCallEncrypter(sd)
+	if err != nil {
+		return nil, err
+	}
+	return encryptStructWith(call, instance)
+}
+
+// DecryptStruct crawls all anottated struct properties and decrypts them in
+// place, unsealing each field's data key with the private key.
+func (sd hybridSecureData) DecryptStruct(encryptedInstance interface{}) (interface{}, error) {
+	return decryptStructWith(sd, encryptedInstance)
+}
+
+func (sd hybridSecureData) encrypt(plaintext []byte) ([]byte, error) {
+	if sd.pub == nil {
+		return nil, errors.New("hybrid: no public key configured for encryption")
+	}
+
+	dataKey := make([]byte, hybridKeySize)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return nil, err
+	}
+
+	wrappedKey, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, sd.pub, dataKey, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return sealWithDataKey(dataKey, wrappedKey, plaintext)
+}
+
+// sealWithDataKey seals plaintext under dataKey with AES-GCM and a fresh
+// nonce, framing it with wrappedKey - the RSA-OAEP-wrapped form of dataKey -
+// in the wire format documented on Encrypt. It is shared by hybridSecureData,
+// which wraps a fresh dataKey per call, and hybridCallEncrypter, which reuses
+// the same dataKey/wrappedKey pair across every field in a struct walk.
+func sealWithDataKey(dataKey, wrappedKey, plaintext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, 0, 1+2+len(wrappedKey)+len(nonce)+len(plaintext)+gcm.Overhead())
+	out = append(out, hybridWireVersion)
+	out = append(out, byte(len(wrappedKey)>>8), byte(len(wrappedKey)))
+	out = append(out, wrappedKey...)
+	out = append(out, nonce...)
+	out = gcm.Seal(out, nonce, plaintext, nil)
+
+	return out, nil
+}
+
+func (sd hybridSecureData) decrypt(ciphertext []byte) ([]byte, error) {
+	if sd.priv == nil {
+		return nil, ErrNoPrivateKey
+	}
+
+	if len(ciphertext) < 3 || ciphertext[0] != hybridWireVersion {
+		return nil, ErrInvalidAlgorithm
+	}
+
+	wrappedKeyLen := int(binary.BigEndian.Uint16(ciphertext[1:3]))
+	rest := ciphertext[3:]
+	if len(rest) < wrappedKeyLen {
+		return nil, ErrInvalidAlgorithm
+	}
+
+	wrappedKey := rest[:wrappedKeyLen]
+	rest = rest[wrappedKeyLen:]
+
+	dataKey, err := rsa.DecryptOAEP(sha256.New(), rand.Reader, sd.priv, wrappedKey, nil)
+	if err != nil {
+		return nil, ErrAuthenticationFailed
+	}
+
+	block, err := aes.NewCipher(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rest) < gcm.NonceSize() {
+		return nil, ErrInvalidAlgorithm
+	}
+
+	nonce := rest[:gcm.NonceSize()]
+	sealed := rest[gcm.NonceSize():]
+
+	plaintext, err := gcm.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return nil, ErrAuthenticationFailed
+	}
+
+	return plaintext, nil
+}
+
+// hybridCallEncrypter is the Crypto that hybridSecureData.EncryptStruct hands
+// to encryptStructWith instead of itself: it carries one data key and its
+// RSA-OAEP wrapping, generated once, and reuses both for every field Encrypt
+// is called on for the rest of the walk - including fields of nested structs,
+// since its EncryptStruct recurses with the same call-scoped key rather than
+// minting a new one. Decrypt/DecryptStruct are unaffected by any of this and
+// simply defer to the embedded hybridSecureData, since unwrapping a field's
+// data key is already a one-time cost paid independently per field.
+type hybridCallEncrypter struct {
+	hybridSecureData
+	dataKey    []byte
+	wrappedKey []byte
+}
+
+func newHybridCallEncrypter(sd hybridSecureData) (*hybridCallEncrypter, error) {
+	if sd.pub == nil {
+		return nil, errors.New("hybrid: no public key configured for encryption")
+	}
+
+	dataKey := make([]byte, hybridKeySize)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return nil, err
+	}
+
+	wrappedKey, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, sd.pub, dataKey, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &hybridCallEncrypter{hybridSecureData: sd, dataKey: dataKey, wrappedKey: wrappedKey}, nil
+}
+
+// Encrypt seals text under the call's shared data key instead of generating
+// a fresh one, reusing only the already-computed RSA-OAEP wrapping.
+func (c *hybridCallEncrypter) Encrypt(text string) (string, error) {
+	sealed, err := sealWithDataKey(c.dataKey, c.wrappedKey, []byte(text))
+	return string(sealed), err
+}
+
+// EncryptStruct recurses with the same call-scoped key and data key
+// wrapping, so nested structs share it too instead of each minting their own.
+func (c *hybridCallEncrypter) EncryptStruct(instance interface{}) (interface{}, error) {
+	return encryptStructWith(c, instance)
+}
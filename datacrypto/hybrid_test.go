@@ -0,0 +1,128 @@
+package datacrypto
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"testing"
+)
+
+func mustGenerateTestRSAKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("unexpected error generating RSA key: %v", err)
+	}
+
+	return priv
+}
+
+func Test_hybridSecureData_EncryptDecrypt_roundtrip(t *testing.T) {
+	priv := mustGenerateTestRSAKey(t)
+	sd := NewHybridRSA(&priv.PublicKey, priv)
+
+	encrypted, err := sd.Encrypt(value1)
+	if err != nil {
+		t.Fatalf("unexpected error encrypting: %v", err)
+	}
+
+	if encrypted == value1 {
+		t.Fatalf("expected ciphertext to differ from plaintext")
+	}
+
+	decrypted, err := sd.Decrypt(encrypted)
+	if err != nil {
+		t.Fatalf("unexpected error decrypting: %v", err)
+	}
+
+	if decrypted != value1 {
+		t.Fatalf("got %q, want %q", decrypted, value1)
+	}
+}
+
+func Test_hybridSecureData_Decrypt_requiresPrivateKey(t *testing.T) {
+	priv := mustGenerateTestRSAKey(t)
+	encryptor := NewHybridRSA(&priv.PublicKey, nil)
+
+	encrypted, err := encryptor.Encrypt(value1)
+	if err != nil {
+		t.Fatalf("unexpected error encrypting: %v", err)
+	}
+
+	if _, err := encryptor.Decrypt(encrypted); !errors.Is(err, ErrNoPrivateKey) {
+		t.Fatalf("got error %v, want %v", err, ErrNoPrivateKey)
+	}
+}
+
+func Test_hybridSecureData_EncryptStruct_DecryptStruct_roundtrip(t *testing.T) {
+	priv := mustGenerateTestRSAKey(t)
+	sd := NewHybridRSA(&priv.PublicKey, priv)
+
+	instance := &TestStruct{
+		Field1: value1,
+		Field2: value2,
+		Field3: value3,
+	}
+
+	if _, err := sd.EncryptStruct(instance); err != nil {
+		t.Fatalf("unexpected error encrypting struct: %v", err)
+	}
+
+	if instance.Field1 == value1 || instance.Field3 == value3 {
+		t.Fatalf("expected annotated fields to be encrypted")
+	}
+
+	if instance.Field2 != value2 {
+		t.Fatalf("expected unannotated field to be left untouched")
+	}
+
+	if _, err := sd.DecryptStruct(instance); err != nil {
+		t.Fatalf("unexpected error decrypting struct: %v", err)
+	}
+
+	if instance.Field1 != value1 || instance.Field2 != value2 || instance.Field3 != value3 {
+		t.Fatalf("got %+v, want round-tripped values", instance)
+	}
+}
+
+// Test_hybridSecureData_EncryptStruct_sharesDataKeyAcrossFields guards
+// against a regression to per-field data keys: EncryptStruct should wrap one
+// data key per call and reuse it for every annotated field, not mint and
+// RSA-OAEP-wrap a fresh one for each, so the wrapped-key prefix embedded in
+// every field's ciphertext must be identical.
+func Test_hybridSecureData_EncryptStruct_sharesDataKeyAcrossFields(t *testing.T) {
+	priv := mustGenerateTestRSAKey(t)
+	sd := NewHybridRSA(&priv.PublicKey, priv)
+
+	instance := &TestStruct{Field1: value1, Field2: value2, Field3: value3}
+
+	if _, err := sd.EncryptStruct(instance); err != nil {
+		t.Fatalf("unexpected error encrypting struct: %v", err)
+	}
+
+	wrappedKey1 := hybridWrappedKeyPrefix(t, instance.Field1)
+	wrappedKey3 := hybridWrappedKeyPrefix(t, instance.Field3)
+
+	if wrappedKey1 != wrappedKey3 {
+		t.Fatalf("expected every field to share the same wrapped data key, got %q and %q", wrappedKey1, wrappedKey3)
+	}
+}
+
+// hybridWrappedKeyPrefix extracts the RSA-OAEP-wrapped data key prefix from
+// a hybridSecureData ciphertext, per the wire format documented on Encrypt.
+func hybridWrappedKeyPrefix(t *testing.T, ciphertext string) string {
+	t.Helper()
+
+	data := []byte(ciphertext)
+	if len(data) < 3 {
+		t.Fatalf("ciphertext too short: %q", ciphertext)
+	}
+
+	wrappedKeyLen := int(data[1])<<8 | int(data[2])
+	if len(data) < 3+wrappedKeyLen {
+		t.Fatalf("ciphertext too short for its own wrapped-key length: %q", ciphertext)
+	}
+
+	return string(data[3 : 3+wrappedKeyLen])
+}
@@ -0,0 +1,202 @@
+package datacrypto
+
+import (
+	"errors"
+	"testing"
+)
+
+var testKDFSalt = []byte("test-salt-0123456789ab")
+
+func Test_kdfGCMSecureData_scrypt_roundtrip(t *testing.T) {
+	sd := NewAES256GCMWithScrypt("correct horse battery staple", testKDFSalt, ScryptParams{N: 16384, R: 8, P: 1})
+
+	encrypted, err := sd.Encrypt(value1)
+	if err != nil {
+		t.Fatalf("unexpected error encrypting: %v", err)
+	}
+
+	decrypted, err := sd.Decrypt(encrypted)
+	if err != nil {
+		t.Fatalf("unexpected error decrypting: %v", err)
+	}
+
+	if decrypted != value1 {
+		t.Fatalf("got %q, want %q", decrypted, value1)
+	}
+}
+
+func Test_kdfGCMSecureData_pbkdf2_roundtrip(t *testing.T) {
+	sd := NewAES256GCMWithPBKDF2("correct horse battery staple", testKDFSalt, PBKDF2Params{Iterations: 100000})
+
+	encrypted, err := sd.Encrypt(value1)
+	if err != nil {
+		t.Fatalf("unexpected error encrypting: %v", err)
+	}
+
+	decrypted, err := sd.Decrypt(encrypted)
+	if err != nil {
+		t.Fatalf("unexpected error decrypting: %v", err)
+	}
+
+	if decrypted != value1 {
+		t.Fatalf("got %q, want %q", decrypted, value1)
+	}
+}
+
+func Test_kdfGCMSecureData_argon2id_roundtrip(t *testing.T) {
+	sd := NewAES256GCMWithArgon2id("correct horse battery staple", testKDFSalt, Argon2idParams{Time: 1, Memory: 64 * 1024, Threads: 4})
+
+	encrypted, err := sd.Encrypt(value1)
+	if err != nil {
+		t.Fatalf("unexpected error encrypting: %v", err)
+	}
+
+	decrypted, err := sd.Decrypt(encrypted)
+	if err != nil {
+		t.Fatalf("unexpected error decrypting: %v", err)
+	}
+
+	if decrypted != value1 {
+		t.Fatalf("got %q, want %q", decrypted, value1)
+	}
+}
+
+func Test_kdfGCMSecureData_scrypt_invalidParamsErrorsInsteadOfPanicking(t *testing.T) {
+	sd := NewAES256GCMWithScrypt("correct horse battery staple", testKDFSalt, ScryptParams{})
+
+	if _, err := sd.Encrypt(value1); err == nil {
+		t.Fatalf("expected an error for invalid scrypt params, got nil")
+	}
+
+	if _, err := sd.Decrypt("anything"); err == nil {
+		t.Fatalf("expected an error for invalid scrypt params, got nil")
+	}
+
+	if _, err := sd.EncryptStruct(&TestStruct{Field1: value1}); err == nil {
+		t.Fatalf("expected an error for invalid scrypt params, got nil")
+	}
+}
+
+func Test_kdfGCMSecureData_Decrypt_rejectsMismatchedSalt(t *testing.T) {
+	sd := NewAES256GCMWithScrypt("correct horse battery staple", testKDFSalt, ScryptParams{N: 16384, R: 8, P: 1})
+	other := NewAES256GCMWithScrypt("correct horse battery staple", []byte("a different salt value"), ScryptParams{N: 16384, R: 8, P: 1})
+
+	encrypted, err := sd.Encrypt(value1)
+	if err != nil {
+		t.Fatalf("unexpected error encrypting: %v", err)
+	}
+
+	if _, err := other.Decrypt(encrypted); !errors.Is(err, ErrInvalidAlgorithm) {
+		t.Fatalf("got error %v, want %v", err, ErrInvalidAlgorithm)
+	}
+}
+
+func Test_kdfGCMSecureData_deterministicField_isStableAndRoundtrips(t *testing.T) {
+	sd := NewAES256GCMWithScrypt("correct horse battery staple", testKDFSalt, ScryptParams{N: 16384, R: 8, P: 1})
+
+	first := &testDeterministicStruct{Field1: value1, Field2: value2}
+	second := &testDeterministicStruct{Field1: value1, Field2: value2}
+
+	if _, err := sd.EncryptStruct(first); err != nil {
+		t.Fatalf("unexpected error encrypting: %v", err)
+	}
+	if _, err := sd.EncryptStruct(second); err != nil {
+		t.Fatalf("unexpected error encrypting: %v", err)
+	}
+
+	if first.Field1 != second.Field1 {
+		t.Fatalf("expected deterministic encryption to produce identical ciphertexts, got %q and %q", first.Field1, second.Field1)
+	}
+
+	if _, err := sd.DecryptStruct(first); err != nil {
+		t.Fatalf("unexpected error decrypting: %v", err)
+	}
+
+	if first.Field1 != value1 {
+		t.Fatalf("got %q, want %q", first.Field1, value1)
+	}
+}
+
+func Test_kdfGCMSecureData_hashField_isOneWay(t *testing.T) {
+	sd := NewAES256GCMWithScrypt("correct horse battery staple", testKDFSalt, ScryptParams{N: 16384, R: 8, P: 1})
+
+	instance := &testHashStruct{Field1: value1, Field2: value2, Field3: value3}
+
+	if _, err := sd.EncryptStruct(instance); err != nil {
+		t.Fatalf("unexpected error encrypting: %v", err)
+	}
+
+	if instance.Field1 == value1 || instance.Field2 == value2 {
+		t.Fatalf("expected hashed fields to change, got %+v", instance)
+	}
+}
+
+func Test_kdfGCMSecureData_streamField_roundtrips(t *testing.T) {
+	sd := NewAES256GCMWithScrypt("correct horse battery staple", testKDFSalt, ScryptParams{N: 16384, R: 8, P: 1})
+
+	instance := &testStreamStruct{Payload: []byte(value1)}
+
+	if _, err := sd.EncryptStruct(instance); err != nil {
+		t.Fatalf("unexpected error encrypting: %v", err)
+	}
+
+	if string(instance.Payload) == value1 {
+		t.Fatalf("expected stream field to be encrypted")
+	}
+
+	if _, err := sd.DecryptStruct(instance); err != nil {
+		t.Fatalf("unexpected error decrypting: %v", err)
+	}
+
+	if string(instance.Payload) != value1 {
+		t.Fatalf("got %q, want %q", instance.Payload, value1)
+	}
+}
+
+func Test_Rewrap_migratesToNewCrypto(t *testing.T) {
+	oldCrypto := NewAES256GCM(gcmTestKey)
+	newCrypto := NewAES256GCMWithScrypt("correct horse battery staple", testKDFSalt, ScryptParams{N: 16384, R: 8, P: 1})
+
+	instance := &TestStruct{Field1: value1, Field2: value2, Field3: value3}
+
+	if _, err := oldCrypto.EncryptStruct(instance); err != nil {
+		t.Fatalf("unexpected error encrypting: %v", err)
+	}
+
+	if err := Rewrap(oldCrypto, newCrypto, instance); err != nil {
+		t.Fatalf("unexpected error rewrapping: %v", err)
+	}
+
+	if _, err := oldCrypto.DecryptStruct(&TestStruct{Field1: instance.Field1}); err == nil {
+		t.Fatalf("expected the old crypto to no longer be able to decrypt the rewrapped field")
+	}
+
+	if _, err := newCrypto.DecryptStruct(instance); err != nil {
+		t.Fatalf("unexpected error decrypting with the new crypto: %v", err)
+	}
+
+	if instance.Field1 != value1 || instance.Field2 != value2 || instance.Field3 != value3 {
+		t.Fatalf("got %+v, want round-tripped values", instance)
+	}
+}
+
+func Test_Rewrap_leavesInstanceUntouchedOnEncryptFailure(t *testing.T) {
+	oldCrypto := NewAES256GCM(gcmTestKey)
+	newCrypto := NewAES128ECB(testKey)
+
+	instance := &testDeterministicStruct{Field1: value1, Field2: value2}
+
+	if _, err := oldCrypto.EncryptStruct(instance); err != nil {
+		t.Fatalf("unexpected error encrypting: %v", err)
+	}
+
+	encrypted := *instance
+
+	if err := Rewrap(oldCrypto, newCrypto, instance); !errors.Is(err, ErrDeterministicUnsupported) {
+		t.Fatalf("got error %v, want %v", err, ErrDeterministicUnsupported)
+	}
+
+	if *instance != encrypted {
+		t.Fatalf("expected instance to be left holding its old ciphertext after a failed rewrap, got %+v want %+v", instance, encrypted)
+	}
+}
@@ -0,0 +1,665 @@
+package datacrypto
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+)
+
+// errNotStringLike is returned when a field or element carries a crypt tag
+// that requires a string, but the field isn't a string, a pointer to one, or
+// a collection of either.
+var errNotStringLike = errors.New("Field must be a string or a pointer to a string to be decrypted")
+
+// ErrBlobContainerMissing is returned when a struct has one or more
+// `crypt:"blob"` fields but no sibling field tagged `crypt:"blob,container"`
+// to hold their bundled, encrypted payload.
+var ErrBlobContainerMissing = errors.New("datacrypto: blob fields present but no crypt:\"blob,container\" field found")
+
+// encryptStructWith crawls all anottated struct properties of instance and
+// encrypts them in place using c. It is shared by every Crypto implementation
+// in this package so the struct walking logic only needs to be maintained once.
+func encryptStructWith(c Crypto, instance interface{}) (interface{}, error) {
+	instanceType := reflect.TypeOf(instance)
+	if instanceType.Kind() != reflect.Ptr {
+		return nil, errors.New("must receive a pointer, but received " + instanceType.Kind().String())
+	}
+
+	instanceType = instanceType.Elem()
+	if instanceType.Kind() != reflect.Struct {
+		return nil, errors.New("must receive a pointer to a struct, but received " + instanceType.Kind().String())
+	}
+
+	instanceValue := reflect.ValueOf(instance).Elem()
+
+	blob := map[string]string{}
+	var container reflect.Value
+
+	for i := 0; i < instanceType.NumField(); i++ {
+		tag := parseCryptTag(instanceType.Field(i).Tag.Get("crypt"))
+		field := instanceValue.Field(i)
+
+		if !field.IsValid() || !field.CanSet() {
+			continue
+		}
+
+		switch field.Kind() {
+		case reflect.String:
+			if tag.mode == modeBlob {
+				blob[instanceType.Field(i).Name] = field.String()
+				field.SetString("")
+				continue
+			}
+			if err := applyEncryptMode(c, tag, field); err != nil {
+				return nil, err
+			}
+
+		case reflect.Ptr:
+			if !field.IsNil() && field.Elem().IsValid() {
+				switch field.Elem().Kind() {
+				case reflect.String:
+					if err := applyEncryptMode(c, tag, field.Elem()); err != nil {
+						return nil, err
+					}
+				case reflect.Struct:
+					if _, err := c.EncryptStruct(field.Interface()); err != nil {
+						return nil, err
+					}
+				}
+			}
+		case reflect.Struct:
+			if _, err := c.EncryptStruct(field.Addr().Interface()); err != nil {
+				return nil, err
+			}
+		case reflect.Slice:
+			if tag.mode == modeBlobContainer && field.Type().Elem().Kind() == reflect.Uint8 {
+				container = field
+				continue
+			}
+			if tag.stream {
+				if err := encryptStreamField(c, field); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			if err := encryptCollectionElements(c, tag, field); err != nil {
+				return nil, err
+			}
+		case reflect.Array:
+			if err := encryptCollectionElements(c, tag, field); err != nil {
+				return nil, err
+			}
+		case reflect.Map:
+			if err := encryptMapValues(c, tag, field); err != nil {
+				return nil, err
+			}
+		default:
+			if tag.mode != modeNone {
+				return nil, errNotStringLike
+			}
+		}
+	}
+
+	if len(blob) > 0 {
+		if !container.IsValid() {
+			return nil, ErrBlobContainerMissing
+		}
+		if err := sealBlob(c, container, blob); err != nil {
+			return nil, err
+		}
+	}
+
+	return instance, nil
+}
+
+// applyEncryptMode transforms field - a string Value - according to tag's mode.
+func applyEncryptMode(c Crypto, tag cryptTag, field reflect.Value) error {
+	transformed, err := encryptLeafValue(c, tag, field.String())
+	if err != nil {
+		return err
+	}
+	field.SetString(transformed)
+
+	return nil
+}
+
+// encryptLeafValue applies tag's mode to a single string value.
+func encryptLeafValue(c Crypto, tag cryptTag, text string) (string, error) {
+	switch tag.mode {
+	case modeNone:
+		return text, nil
+	case modeEncrypt:
+		return c.Encrypt(text)
+	case modeDeterministic:
+		det, ok := c.(DeterministicEncrypter)
+		if !ok {
+			return "", ErrDeterministicUnsupported
+		}
+		return det.EncryptDeterministic(text)
+	case modeHash:
+		hasher, ok := c.(Hasher)
+		if !ok {
+			return "", ErrHashUnsupported
+		}
+		return hasher.Hash(tag.hashAlgo, text)
+	default:
+		return "", errNotStringLike
+	}
+}
+
+// encryptCollectionElements iterates a slice or array field, encrypting
+// string elements, recursing into struct elements (and pointers to structs),
+// and erroring on annotated elements of any other kind.
+func encryptCollectionElements(c Crypto, tag cryptTag, field reflect.Value) error {
+	elemType := field.Type().Elem()
+
+	if elemType.Kind() == reflect.Uint8 {
+		if tag.mode != modeNone {
+			return errNotStringLike
+		}
+		return nil
+	}
+
+	for i := 0; i < field.Len(); i++ {
+		elem := field.Index(i)
+
+		switch elemType.Kind() {
+		case reflect.String:
+			if err := applyEncryptMode(c, tag, elem); err != nil {
+				return err
+			}
+		case reflect.Struct:
+			if _, err := c.EncryptStruct(elem.Addr().Interface()); err != nil {
+				return err
+			}
+		case reflect.Ptr:
+			if elemType.Elem().Kind() == reflect.Struct {
+				if !elem.IsNil() {
+					if _, err := c.EncryptStruct(elem.Interface()); err != nil {
+						return err
+					}
+				}
+			} else if tag.mode != modeNone {
+				return errNotStringLike
+			}
+		default:
+			if tag.mode != modeNone {
+				return errNotStringLike
+			}
+		}
+	}
+
+	return nil
+}
+
+// encryptMapValues replaces field - a map with string keys - with a new map
+// holding the encrypted/hashed values (and, if tag.keys is set, keys). A new
+// map is built because reflect.Value.MapIndex returns unaddressable values,
+// so entries cannot be rewritten in place.
+func encryptMapValues(c Crypto, tag cryptTag, field reflect.Value) error {
+	if field.IsNil() || field.Type().Key().Kind() != reflect.String {
+		return nil
+	}
+
+	elemType := field.Type().Elem()
+	newMap := reflect.MakeMapWithSize(field.Type(), field.Len())
+
+	iter := field.MapRange()
+	for iter.Next() {
+		key := iter.Key()
+		value := iter.Value()
+
+		newKey := key
+		if tag.keys {
+			encryptedKey, err := encryptLeafValue(c, tag, key.String())
+			if err != nil {
+				return err
+			}
+			newKey = reflect.ValueOf(encryptedKey)
+		}
+
+		switch elemType.Kind() {
+		case reflect.String:
+			encrypted, err := encryptLeafValue(c, tag, value.String())
+			if err != nil {
+				return err
+			}
+			newMap.SetMapIndex(newKey, reflect.ValueOf(encrypted))
+		case reflect.Struct:
+			valueCopy := reflect.New(elemType)
+			valueCopy.Elem().Set(value)
+			if _, err := c.EncryptStruct(valueCopy.Interface()); err != nil {
+				return err
+			}
+			newMap.SetMapIndex(newKey, valueCopy.Elem())
+		default:
+			if tag.mode != modeNone {
+				return errNotStringLike
+			}
+			newMap.SetMapIndex(newKey, value)
+		}
+	}
+
+	field.Set(newMap)
+
+	return nil
+}
+
+// encryptStreamField replaces field - a []byte - with its chunked, encrypted
+// form, produced via c's StreamCrypto implementation.
+func encryptStreamField(c Crypto, field reflect.Value) error {
+	if field.Type().Elem().Kind() != reflect.Uint8 {
+		return errNotStringLike
+	}
+
+	streamer, ok := c.(StreamCrypto)
+	if !ok {
+		return ErrStreamUnsupported
+	}
+
+	var buf bytes.Buffer
+	if err := streamer.EncryptStream(&buf, bytes.NewReader(field.Bytes())); err != nil {
+		return err
+	}
+
+	field.SetBytes(buf.Bytes())
+
+	return nil
+}
+
+// decryptStreamField reverses encryptStreamField.
+func decryptStreamField(c Crypto, field reflect.Value) error {
+	if field.Type().Elem().Kind() != reflect.Uint8 {
+		return errNotStringLike
+	}
+
+	streamer, ok := c.(StreamCrypto)
+	if !ok {
+		return ErrStreamUnsupported
+	}
+
+	var buf bytes.Buffer
+	if err := streamer.DecryptStream(&buf, bytes.NewReader(field.Bytes())); err != nil {
+		return err
+	}
+
+	field.SetBytes(buf.Bytes())
+
+	return nil
+}
+
+// sealBlob JSON-encodes the gathered blob fields, encrypts the result with c
+// and stores it in container.
+func sealBlob(c Crypto, container reflect.Value, blob map[string]string) error {
+	payload, err := json.Marshal(blob)
+	if err != nil {
+		return err
+	}
+
+	encrypted, err := c.Encrypt(string(payload))
+	if err != nil {
+		return err
+	}
+
+	container.SetBytes([]byte(encrypted))
+
+	return nil
+}
+
+// decryptStructWith crawls all anottated struct properties of
+// encryptedInstance and decrypts them in place using c. Decryption is first
+// performed on a deep copy of encryptedInstance - not just a shallow struct
+// copy, since that would still alias the original's pointer targets and
+// slice/map backing storage - so an authentication failure on one field
+// never touches the caller's struct at all. Once every field has decrypted
+// successfully, the results are merged back onto encryptedInstance field by
+// field via mergeDecryptedValue rather than overwritten wholesale: pointer
+// and nested-struct fields are updated through the caller's existing
+// pointers, preserving their identity for any other alias to the same data,
+// and fields deepCopyValue left untouched (unexported ones) are never
+// merged back, so they're never clobbered either.
+func decryptStructWith(c Crypto, encryptedInstance interface{}) (interface{}, error) {
+	instanceType := reflect.TypeOf(encryptedInstance)
+	if instanceType.Kind() != reflect.Ptr {
+		return nil, errors.New("must receive a pointer, but received " + instanceType.Kind().String())
+	}
+
+	instanceType = instanceType.Elem()
+	if instanceType.Kind() != reflect.Struct {
+		return nil, errors.New("must receive a pointer to a struct, but received " + instanceType.Kind().String())
+	}
+
+	clone := reflect.New(instanceType)
+	clone.Elem().Set(deepCopyValue(reflect.ValueOf(encryptedInstance).Elem()))
+
+	if _, err := decryptStructInPlace(c, clone.Interface()); err != nil {
+		return nil, err
+	}
+
+	mergeDecryptedValue(reflect.ValueOf(encryptedInstance).Elem(), clone.Elem())
+
+	return encryptedInstance, nil
+}
+
+// mergeDecryptedValue copies the decrypted scratch value produced by
+// decryptStructWith back onto orig, the caller's original value. Unlike a
+// wholesale reflect.Value.Set, it recurses into pointers, structs, slices
+// and arrays and assigns leaf by leaf: a pointer field is merged into its
+// existing pointee in place instead of being replaced with decrypted's
+// freshly-allocated one, so its identity (and anything else aliasing it)
+// survives; and orig.CanSet() being false - true of every unexported field -
+// simply stops the recursion there, leaving that part of orig untouched
+// rather than zeroed.
+func mergeDecryptedValue(orig, decrypted reflect.Value) {
+	if !orig.CanSet() {
+		return
+	}
+
+	switch orig.Kind() {
+	case reflect.Ptr:
+		if orig.IsNil() || decrypted.IsNil() {
+			return
+		}
+		mergeDecryptedValue(orig.Elem(), decrypted.Elem())
+	case reflect.Struct:
+		for i := 0; i < orig.NumField(); i++ {
+			mergeDecryptedValue(orig.Field(i), decrypted.Field(i))
+		}
+	case reflect.Slice:
+		if orig.IsNil() != decrypted.IsNil() || orig.Len() != decrypted.Len() {
+			orig.Set(decrypted)
+			return
+		}
+		for i := 0; i < orig.Len(); i++ {
+			mergeDecryptedValue(orig.Index(i), decrypted.Index(i))
+		}
+	case reflect.Array:
+		for i := 0; i < orig.Len(); i++ {
+			mergeDecryptedValue(orig.Index(i), decrypted.Index(i))
+		}
+	default:
+		orig.Set(decrypted)
+	}
+}
+
+// deepCopyValue returns a copy of v that shares no mutable storage with it.
+// decryptStructWith uses this to build the scratch copy it decrypts into:
+// reflect.Value.Set alone performs a shallow copy, so pointer fields and
+// slice/map headers would still point at the original's backing storage and
+// decryptStructInPlace would mutate it directly. Only the kinds the walker
+// ever descends into - pointers, structs, slices, arrays and maps - need
+// duplicating; every other kind is already copied by value. Unexported
+// fields are deliberately left zero-valued (mergeDecryptedValue never reads
+// them back out of the clone, so this is never observed by the caller).
+func deepCopyValue(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v
+		}
+		cp := reflect.New(v.Type().Elem())
+		cp.Elem().Set(deepCopyValue(v.Elem()))
+		return cp
+	case reflect.Struct:
+		cp := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.NumField(); i++ {
+			field := cp.Field(i)
+			if !field.CanSet() {
+				continue
+			}
+			field.Set(deepCopyValue(v.Field(i)))
+		}
+		return cp
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		cp := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			cp.Index(i).Set(deepCopyValue(v.Index(i)))
+		}
+		return cp
+	case reflect.Array:
+		cp := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			cp.Index(i).Set(deepCopyValue(v.Index(i)))
+		}
+		return cp
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		cp := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			cp.SetMapIndex(iter.Key(), deepCopyValue(iter.Value()))
+		}
+		return cp
+	default:
+		return v
+	}
+}
+
+// decryptStructInPlace performs the actual field walk, mutating instance directly.
+func decryptStructInPlace(c Crypto, instance interface{}) (interface{}, error) {
+	instanceType := reflect.TypeOf(instance).Elem()
+	instanceValue := reflect.ValueOf(instance).Elem()
+
+	var blob map[string]string
+
+	for i := 0; i < instanceType.NumField(); i++ {
+		tag := parseCryptTag(instanceType.Field(i).Tag.Get("crypt"))
+		field := instanceValue.Field(i)
+
+		if !field.IsValid() || !field.CanSet() {
+			continue
+		}
+
+		switch field.Kind() {
+		case reflect.String:
+			if tag.mode == modeBlob {
+				continue
+			}
+			if err := applyDecryptMode(c, tag, field); err != nil {
+				return nil, err
+			}
+
+		case reflect.Ptr:
+			if !field.IsNil() && field.Elem().IsValid() {
+				switch field.Elem().Kind() {
+				case reflect.String:
+					if err := applyDecryptMode(c, tag, field.Elem()); err != nil {
+						return nil, err
+					}
+				case reflect.Struct:
+					if _, err := decryptStructInPlace(c, field.Interface()); err != nil {
+						return nil, err
+					}
+				}
+			}
+		case reflect.Struct:
+			if _, err := decryptStructInPlace(c, field.Addr().Interface()); err != nil {
+				return nil, err
+			}
+		case reflect.Slice:
+			if tag.mode == modeBlobContainer && field.Type().Elem().Kind() == reflect.Uint8 && field.Len() > 0 {
+				b, err := unsealBlob(c, field)
+				if err != nil {
+					return nil, err
+				}
+				blob = b
+				continue
+			}
+			if tag.stream {
+				if err := decryptStreamField(c, field); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			if err := decryptCollectionElements(c, tag, field); err != nil {
+				return nil, err
+			}
+		case reflect.Array:
+			if err := decryptCollectionElements(c, tag, field); err != nil {
+				return nil, err
+			}
+		case reflect.Map:
+			if err := decryptMapValues(c, tag, field); err != nil {
+				return nil, err
+			}
+		default:
+			if tag.mode != modeNone {
+				return nil, errNotStringLike
+			}
+		}
+	}
+
+	if blob != nil {
+		for i := 0; i < instanceType.NumField(); i++ {
+			tag := parseCryptTag(instanceType.Field(i).Tag.Get("crypt"))
+			if tag.mode != modeBlob {
+				continue
+			}
+			field := instanceValue.Field(i)
+			if field.IsValid() && field.CanSet() {
+				field.SetString(blob[instanceType.Field(i).Name])
+			}
+		}
+	}
+
+	return instance, nil
+}
+
+// applyDecryptMode reverses applyEncryptMode for field - a string Value.
+// Hashes are one-way and are left untouched.
+func applyDecryptMode(c Crypto, tag cryptTag, field reflect.Value) error {
+	decrypted, err := decryptLeafValue(c, tag, field.String())
+	if err != nil {
+		return err
+	}
+	field.SetString(decrypted)
+
+	return nil
+}
+
+// decryptLeafValue reverses encryptLeafValue for a single string value.
+// Hashes are one-way and are returned unchanged.
+func decryptLeafValue(c Crypto, tag cryptTag, text string) (string, error) {
+	switch tag.mode {
+	case modeNone, modeHash:
+		return text, nil
+	case modeEncrypt, modeDeterministic:
+		return c.Decrypt(text)
+	default:
+		return "", errNotStringLike
+	}
+}
+
+// decryptCollectionElements mirrors encryptCollectionElements for decryption.
+func decryptCollectionElements(c Crypto, tag cryptTag, field reflect.Value) error {
+	elemType := field.Type().Elem()
+
+	if elemType.Kind() == reflect.Uint8 {
+		if tag.mode != modeNone {
+			return errNotStringLike
+		}
+		return nil
+	}
+
+	for i := 0; i < field.Len(); i++ {
+		elem := field.Index(i)
+
+		switch elemType.Kind() {
+		case reflect.String:
+			if err := applyDecryptMode(c, tag, elem); err != nil {
+				return err
+			}
+		case reflect.Struct:
+			if _, err := decryptStructInPlace(c, elem.Addr().Interface()); err != nil {
+				return err
+			}
+		case reflect.Ptr:
+			if elemType.Elem().Kind() == reflect.Struct {
+				if !elem.IsNil() {
+					if _, err := decryptStructInPlace(c, elem.Interface()); err != nil {
+						return err
+					}
+				}
+			} else if tag.mode != modeNone {
+				return errNotStringLike
+			}
+		default:
+			if tag.mode != modeNone {
+				return errNotStringLike
+			}
+		}
+	}
+
+	return nil
+}
+
+// decryptMapValues mirrors encryptMapValues for decryption.
+func decryptMapValues(c Crypto, tag cryptTag, field reflect.Value) error {
+	if field.IsNil() || field.Type().Key().Kind() != reflect.String {
+		return nil
+	}
+
+	elemType := field.Type().Elem()
+	newMap := reflect.MakeMapWithSize(field.Type(), field.Len())
+
+	iter := field.MapRange()
+	for iter.Next() {
+		key := iter.Key()
+		value := iter.Value()
+
+		newKey := key
+		if tag.keys {
+			decryptedKey, err := decryptLeafValue(c, tag, key.String())
+			if err != nil {
+				return err
+			}
+			newKey = reflect.ValueOf(decryptedKey)
+		}
+
+		switch elemType.Kind() {
+		case reflect.String:
+			decrypted, err := decryptLeafValue(c, tag, value.String())
+			if err != nil {
+				return err
+			}
+			newMap.SetMapIndex(newKey, reflect.ValueOf(decrypted))
+		case reflect.Struct:
+			valueCopy := reflect.New(elemType)
+			valueCopy.Elem().Set(value)
+			if _, err := decryptStructInPlace(c, valueCopy.Interface()); err != nil {
+				return err
+			}
+			newMap.SetMapIndex(newKey, valueCopy.Elem())
+		default:
+			if tag.mode != modeNone {
+				return errNotStringLike
+			}
+			newMap.SetMapIndex(newKey, value)
+		}
+	}
+
+	field.Set(newMap)
+
+	return nil
+}
+
+// unsealBlob decrypts container and JSON-decodes the resulting bundle of blob fields.
+func unsealBlob(c Crypto, container reflect.Value) (map[string]string, error) {
+	decrypted, err := c.Decrypt(string(container.Bytes()))
+	if err != nil {
+		return nil, err
+	}
+
+	blob := map[string]string{}
+	if err := json.Unmarshal([]byte(decrypted), &blob); err != nil {
+		return nil, err
+	}
+
+	return blob, nil
+}
@@ -0,0 +1,220 @@
+package datacrypto
+
+import (
+	"reflect"
+	"testing"
+)
+
+func roundtripSlices(t *testing.T, sd Crypto) {
+	t.Helper()
+
+	instance := &struct {
+		Strings []string `crypt:"true"`
+	}{
+		Strings: []string{value1, value3},
+	}
+
+	if _, err := sd.EncryptStruct(instance); err != nil {
+		t.Fatalf("unexpected error encrypting: %v", err)
+	}
+
+	if instance.Strings[0] == value1 || instance.Strings[1] == value3 {
+		t.Fatalf("expected slice elements to be encrypted, got %+v", instance.Strings)
+	}
+
+	if _, err := sd.DecryptStruct(instance); err != nil {
+		t.Fatalf("unexpected error decrypting: %v", err)
+	}
+
+	if instance.Strings[0] != value1 || instance.Strings[1] != value3 {
+		t.Fatalf("got %+v, want round-tripped values", instance.Strings)
+	}
+}
+
+func Test_EncryptStruct_DecryptStruct_sliceOfStrings(t *testing.T) {
+	roundtripSlices(t, NewAES128ECB(testKey))
+}
+
+func Test_EncryptStruct_DecryptStruct_sliceOfStructs(t *testing.T) {
+	sd := NewAES128ECB(testKey)
+
+	instance := &struct {
+		Items []TestStruct
+	}{
+		Items: []TestStruct{
+			{Field1: value1, Field2: value2, Field3: value3},
+			{Field1: value3, Field2: value2, Field3: value1},
+		},
+	}
+
+	if _, err := sd.EncryptStruct(instance); err != nil {
+		t.Fatalf("unexpected error encrypting: %v", err)
+	}
+
+	want := []TestStruct{
+		{Field1: encryptedValue1, Field2: value2, Field3: encryptedValue3},
+		{Field1: encryptedValue3, Field2: value2, Field3: encryptedValue1},
+	}
+
+	if !reflect.DeepEqual(instance.Items, want) {
+		t.Fatalf("got %+v, want %+v", instance.Items, want)
+	}
+
+	if _, err := sd.DecryptStruct(instance); err != nil {
+		t.Fatalf("unexpected error decrypting: %v", err)
+	}
+
+	wantDecrypted := []TestStruct{
+		{Field1: value1, Field2: value2, Field3: value3},
+		{Field1: value3, Field2: value2, Field3: value1},
+	}
+
+	if !reflect.DeepEqual(instance.Items, wantDecrypted) {
+		t.Fatalf("got %+v, want %+v", instance.Items, wantDecrypted)
+	}
+}
+
+func Test_EncryptStruct_DecryptStruct_sliceOfStructPointers(t *testing.T) {
+	sd := NewAES128ECB(testKey)
+
+	instance := &struct {
+		Items []*TestStruct
+	}{
+		Items: []*TestStruct{
+			{Field1: value1, Field2: value2, Field3: value3},
+		},
+	}
+
+	if _, err := sd.EncryptStruct(instance); err != nil {
+		t.Fatalf("unexpected error encrypting: %v", err)
+	}
+
+	if instance.Items[0].Field1 != encryptedValue1 || instance.Items[0].Field3 != encryptedValue3 {
+		t.Fatalf("expected pointed-to struct to be encrypted, got %+v", instance.Items[0])
+	}
+
+	if _, err := sd.DecryptStruct(instance); err != nil {
+		t.Fatalf("unexpected error decrypting: %v", err)
+	}
+
+	if instance.Items[0].Field1 != value1 || instance.Items[0].Field3 != value3 {
+		t.Fatalf("got %+v, want round-tripped values", instance.Items[0])
+	}
+}
+
+func Test_EncryptStruct_DecryptStruct_mapOfStructs(t *testing.T) {
+	sd := NewAES128ECB(testKey)
+
+	instance := &struct {
+		Items map[string]TestStruct
+	}{
+		Items: map[string]TestStruct{
+			"a": {Field1: value1, Field2: value2, Field3: value3},
+		},
+	}
+
+	if _, err := sd.EncryptStruct(instance); err != nil {
+		t.Fatalf("unexpected error encrypting: %v", err)
+	}
+
+	if instance.Items["a"].Field1 != encryptedValue1 || instance.Items["a"].Field3 != encryptedValue3 {
+		t.Fatalf("expected map value to be encrypted, got %+v", instance.Items["a"])
+	}
+
+	if _, err := sd.DecryptStruct(instance); err != nil {
+		t.Fatalf("unexpected error decrypting: %v", err)
+	}
+
+	if instance.Items["a"].Field1 != value1 || instance.Items["a"].Field3 != value3 {
+		t.Fatalf("got %+v, want round-tripped values", instance.Items["a"])
+	}
+}
+
+func Test_EncryptStruct_DecryptStruct_mapWithEncryptedKeys(t *testing.T) {
+	sd := NewAES128ECB(testKey)
+
+	instance := &struct {
+		Items map[string]string `crypt:"true,keys"`
+	}{
+		Items: map[string]string{value1: value3},
+	}
+
+	if _, err := sd.EncryptStruct(instance); err != nil {
+		t.Fatalf("unexpected error encrypting: %v", err)
+	}
+
+	if _, ok := instance.Items[value1]; ok {
+		t.Fatalf("expected the original key to no longer be present, got %+v", instance.Items)
+	}
+
+	if _, ok := instance.Items[encryptedValue1]; !ok {
+		t.Fatalf("expected the encrypted key to be present, got %+v", instance.Items)
+	}
+
+	if instance.Items[encryptedValue1] != encryptedValue3 {
+		t.Fatalf("expected the value under the encrypted key to be encrypted too, got %+v", instance.Items)
+	}
+
+	if _, err := sd.DecryptStruct(instance); err != nil {
+		t.Fatalf("unexpected error decrypting: %v", err)
+	}
+
+	if instance.Items[value1] != value3 {
+		t.Fatalf("got %+v, want %+v", instance.Items, map[string]string{value1: value3})
+	}
+}
+
+// Test_DecryptStruct_preservesUnexportedFields guards against a regression
+// in decryptStructWith's atomic clone-and-merge: its scratch clone leaves
+// unexported fields zero-valued (they're never walked), so merging the
+// decrypted result back must skip them too, rather than overwriting the
+// caller's struct wholesale and zeroing them out.
+func Test_DecryptStruct_preservesUnexportedFields(t *testing.T) {
+	sd := NewAES128ECB(testKey)
+
+	instance := &testStructWithUnexportedField{Name: value1, secret: "do not touch"}
+
+	if _, err := sd.EncryptStruct(instance); err != nil {
+		t.Fatalf("unexpected error encrypting: %v", err)
+	}
+	if _, err := sd.DecryptStruct(instance); err != nil {
+		t.Fatalf("unexpected error decrypting: %v", err)
+	}
+
+	if instance.Name != value1 {
+		t.Fatalf("got Name %q, want round-tripped value", instance.Name)
+	}
+	if instance.secret != "do not touch" {
+		t.Fatalf("got secret %q, want it left untouched", instance.secret)
+	}
+}
+
+// Test_DecryptStruct_preservesPointerIdentity guards against a regression in
+// decryptStructWith's atomic clone-and-merge: merging the decrypted result
+// back must update a pointer field's existing pointee in place, not replace
+// the pointer with a freshly-allocated one, or any other alias to the same
+// pointee would keep seeing stale, still-encrypted data.
+func Test_DecryptStruct_preservesPointerIdentity(t *testing.T) {
+	sd := NewAES128ECB(testKey)
+
+	instance := &testRecursiveStructPointer{
+		Field1: value1,
+		Field2: &testRecursiveStruct{Field1: value1, Field2: value2, Field3: TestStruct{Field1: value1, Field2: value2, Field3: value3}},
+	}
+
+	alias := instance.Field2
+
+	if _, err := sd.EncryptStruct(instance); err != nil {
+		t.Fatalf("unexpected error encrypting: %v", err)
+	}
+	if _, err := sd.DecryptStruct(instance); err != nil {
+		t.Fatalf("unexpected error decrypting: %v", err)
+	}
+
+	if instance.Field2 != alias {
+		t.Fatalf("expected Field2 to keep its original pointer identity")
+	}
+	if alias.Field1 != value1 {
+		t.Fatalf("expected the original pointee to observe the decrypted value through the alias, got %+v", alias)
+	}
+}
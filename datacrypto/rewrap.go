@@ -0,0 +1,38 @@
+package datacrypto
+
+import (
+	"errors"
+	"reflect"
+)
+
+// Rewrap decrypts instance with old and re-encrypts it with new in a single
+// struct walk. It is the building block for zero-downtime key or KDF
+// rotation: run it once per record to migrate off an old Crypto onto a new
+// one without a separate decrypt-everything, then re-encrypt-everything pass.
+//
+// Both steps run against a deep copy of instance, not instance itself -
+// the same clone-then-merge approach decryptStructWith uses - so a failure
+// partway through (e.g. new doesn't support a crypt:"true,deterministic" or
+// crypt:"hash" field the struct uses) leaves instance untouched instead of
+// holding plaintext decrypted by old but never re-encrypted by new.
+func Rewrap(old, new Crypto, instance interface{}) error {
+	instanceType := reflect.TypeOf(instance)
+	if instanceType.Kind() != reflect.Ptr || instanceType.Elem().Kind() != reflect.Struct {
+		return errors.New("must receive a pointer to a struct, but received " + instanceType.Kind().String())
+	}
+
+	clone := reflect.New(instanceType.Elem())
+	clone.Elem().Set(deepCopyValue(reflect.ValueOf(instance).Elem()))
+
+	if _, err := old.DecryptStruct(clone.Interface()); err != nil {
+		return err
+	}
+
+	if _, err := new.EncryptStruct(clone.Interface()); err != nil {
+		return err
+	}
+
+	mergeDecryptedValue(reflect.ValueOf(instance).Elem(), clone.Elem())
+
+	return nil
+}
@@ -0,0 +1,198 @@
+package datacrypto
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"io"
+)
+
+// streamChunkSize is the size, in bytes, of the plaintext read per chunk by
+// EncryptStream. The last chunk of a stream may be smaller.
+const streamChunkSize = 64 * 1024
+
+// streamHeaderSize is the width, in bytes, of the per-chunk header: an
+// 8-byte big-endian chunk index followed by a 1-byte final-chunk flag. The
+// header is transmitted in the clear alongside each chunk, but is also fed
+// to GCM as additional authenticated data, so tampering with it - reordering
+// chunks, or truncating the stream before its final chunk - invalidates the
+// chunk's tag instead of silently going undetected.
+const streamHeaderSize = 8 + 1
+
+// EncryptStream implements StreamCrypto. src is read in streamChunkSize
+// blocks; each block is sealed under its own random nonce and written to dst
+// as a self-contained record: the streamHeaderSize-byte header described
+// above, a 4-byte big-endian length, the 12-byte nonce, and the sealed
+// ciphertext (with its tag appended by GCM, covering both the plaintext and
+// the header). Binding a monotonically increasing index and a final-chunk
+// marker into every tag means DecryptStream can detect reordering, dropped
+// chunks and truncation, not just single-chunk corruption - while still
+// authenticating and decrypting one chunk at a time.
+func (sd aesGCMSecureData) EncryptStream(dst io.Writer, src io.Reader) error {
+	gcm, err := sd.gcm()
+	if err != nil {
+		return err
+	}
+
+	buf := make([]byte, streamChunkSize)
+	var pending byte
+	havePending := false
+	var index uint64
+
+	for {
+		n := 0
+		if havePending {
+			buf[0] = pending
+			n = 1
+			havePending = false
+		}
+
+		read, readErr := io.ReadFull(src, buf[n:])
+		n += read
+		if readErr != nil && readErr != io.EOF && readErr != io.ErrUnexpectedEOF {
+			return readErr
+		}
+
+		final := readErr != nil
+		if !final {
+			// buf is exactly full; peek one byte to tell a stream that ends
+			// precisely on a chunk boundary from one that continues.
+			var probe [1]byte
+			peeked, peekErr := io.ReadFull(src, probe[:])
+			if peekErr != nil && peekErr != io.EOF && peekErr != io.ErrUnexpectedEOF {
+				return peekErr
+			}
+			if peeked == 0 {
+				final = true
+			} else {
+				pending, havePending = probe[0], true
+			}
+		}
+
+		if err := writeStreamChunk(dst, gcm, index, final, buf[:n]); err != nil {
+			return err
+		}
+		if final {
+			return nil
+		}
+		index++
+	}
+}
+
+// DecryptStream implements StreamCrypto, reversing EncryptStream's framing.
+// It rejects a stream whose chunk indices skip, repeat or arrive out of
+// order, and a stream that ends before a chunk marked final has been seen,
+// each as ErrAuthenticationFailed.
+func (sd aesGCMSecureData) DecryptStream(dst io.Writer, src io.Reader) error {
+	gcm, err := sd.gcm()
+	if err != nil {
+		return err
+	}
+
+	var header [streamHeaderSize]byte
+	var lengthPrefix [4]byte
+	nonce := make([]byte, gcm.NonceSize())
+
+	var expectedIndex uint64
+	sawFinal := false
+
+	for {
+		if _, err := io.ReadFull(src, header[:]); err != nil {
+			if err == io.EOF && sawFinal {
+				return nil
+			}
+			return streamTruncationErr(err)
+		}
+		if sawFinal {
+			return ErrAuthenticationFailed
+		}
+
+		index := binary.BigEndian.Uint64(header[:8])
+		final := header[8] == 1
+		if index != expectedIndex {
+			return ErrAuthenticationFailed
+		}
+
+		if _, err := io.ReadFull(src, lengthPrefix[:]); err != nil {
+			return streamTruncationErr(err)
+		}
+		if _, err := io.ReadFull(src, nonce); err != nil {
+			return streamTruncationErr(err)
+		}
+
+		sealedLen := binary.BigEndian.Uint32(lengthPrefix[:])
+		if sealedLen > streamChunkSize+uint32(gcm.Overhead()) {
+			// A legitimate chunk, produced by writeStreamChunk, is never
+			// larger than this. Reject before allocating sealed so a
+			// corrupted or adversarial length prefix can't be used to force
+			// an arbitrarily large allocation ahead of authentication.
+			return ErrAuthenticationFailed
+		}
+
+		sealed := make([]byte, sealedLen)
+		if _, err := io.ReadFull(src, sealed); err != nil {
+			return streamTruncationErr(err)
+		}
+
+		plaintext, err := gcm.Open(nil, nonce, sealed, header[:])
+		if err != nil {
+			return ErrAuthenticationFailed
+		}
+
+		if _, err := dst.Write(plaintext); err != nil {
+			return err
+		}
+
+		expectedIndex++
+		sawFinal = final
+	}
+}
+
+// streamTruncationErr maps the end-of-stream errors io.ReadFull can return
+// mid-record - io.EOF or io.ErrUnexpectedEOF, both meaning the stream ended
+// somewhere it shouldn't have - to ErrAuthenticationFailed, so a stream
+// truncated inside a chunk is rejected the same way a corrupted one is,
+// rather than surfacing a raw io error. Any other error (a genuine read
+// failure on src) is passed through unchanged.
+func streamTruncationErr(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return ErrAuthenticationFailed
+	}
+	return err
+}
+
+// writeStreamChunk seals plaintext under a fresh random nonce, authenticating
+// it together with the chunk's index/final header, and writes the resulting
+// [header][length][nonce][ciphertext||tag] record to dst.
+func writeStreamChunk(dst io.Writer, gcm cipher.AEAD, index uint64, final bool, plaintext []byte) error {
+	var header [streamHeaderSize]byte
+	binary.BigEndian.PutUint64(header[:8], index)
+	if final {
+		header[8] = 1
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return err
+	}
+
+	sealed := gcm.Seal(nil, nonce, plaintext, header[:])
+
+	var lengthPrefix [4]byte
+	binary.BigEndian.PutUint32(lengthPrefix[:], uint32(len(sealed)))
+
+	if _, err := dst.Write(header[:]); err != nil {
+		return err
+	}
+	if _, err := dst.Write(lengthPrefix[:]); err != nil {
+		return err
+	}
+	if _, err := dst.Write(nonce); err != nil {
+		return err
+	}
+	if _, err := dst.Write(sealed); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,223 @@
+package datacrypto
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+)
+
+type testStreamStruct struct {
+	Payload []byte `crypt:"true,stream"`
+	Name    string
+}
+
+func Test_aesGCMSecureData_EncryptStream_DecryptStream_roundtrip(t *testing.T) {
+	sd := NewAES256GCM(gcmTestKey)
+
+	plaintext := make([]byte, streamChunkSize*2+123)
+	if _, err := rand.Read(plaintext); err != nil {
+		t.Fatalf("unexpected error generating test data: %v", err)
+	}
+
+	var encrypted bytes.Buffer
+	if err := sd.(StreamCrypto).EncryptStream(&encrypted, bytes.NewReader(plaintext)); err != nil {
+		t.Fatalf("unexpected error encrypting stream: %v", err)
+	}
+
+	var decrypted bytes.Buffer
+	if err := sd.(StreamCrypto).DecryptStream(&decrypted, bytes.NewReader(encrypted.Bytes())); err != nil {
+		t.Fatalf("unexpected error decrypting stream: %v", err)
+	}
+
+	if !bytes.Equal(decrypted.Bytes(), plaintext) {
+		t.Fatalf("got %d bytes back, want %d bytes matching the original", decrypted.Len(), len(plaintext))
+	}
+}
+
+func Test_aesGCMSecureData_DecryptStream_rejectsTamperedChunk(t *testing.T) {
+	sd := NewAES256GCM(gcmTestKey)
+
+	var encrypted bytes.Buffer
+	if err := sd.(StreamCrypto).EncryptStream(&encrypted, bytes.NewReader([]byte("hello stream"))); err != nil {
+		t.Fatalf("unexpected error encrypting stream: %v", err)
+	}
+
+	tampered := encrypted.Bytes()
+	tampered[len(tampered)-1] ^= 0xff
+
+	var decrypted bytes.Buffer
+	err := sd.(StreamCrypto).DecryptStream(&decrypted, bytes.NewReader(tampered))
+	if !errors.Is(err, ErrAuthenticationFailed) {
+		t.Fatalf("got error %v, want %v", err, ErrAuthenticationFailed)
+	}
+}
+
+// splitStreamRecords parses a stream produced by EncryptStream back into its
+// individual [header][length][nonce][sealed] records, so tests can drop or
+// reorder whole chunks without having to re-derive the framing by hand.
+func splitStreamRecords(t *testing.T, sd *aesGCMSecureData, data []byte) [][]byte {
+	t.Helper()
+
+	gcm, err := sd.gcm()
+	if err != nil {
+		t.Fatalf("unexpected error building gcm: %v", err)
+	}
+
+	recordHeaderLen := streamHeaderSize + 4 + gcm.NonceSize()
+
+	var records [][]byte
+	for len(data) > 0 {
+		if len(data) < recordHeaderLen {
+			t.Fatalf("truncated stream record header")
+		}
+		sealedLen := int(binary.BigEndian.Uint32(data[streamHeaderSize : streamHeaderSize+4]))
+		recordLen := recordHeaderLen + sealedLen
+		if recordLen > len(data) {
+			t.Fatalf("truncated stream record body")
+		}
+		records = append(records, data[:recordLen])
+		data = data[recordLen:]
+	}
+	return records
+}
+
+func Test_aesGCMSecureData_DecryptStream_rejectsDroppedFinalChunk(t *testing.T) {
+	sd := NewAES256GCM(gcmTestKey).(*aesGCMSecureData)
+
+	plaintext := make([]byte, streamChunkSize*2+123)
+	if _, err := rand.Read(plaintext); err != nil {
+		t.Fatalf("unexpected error generating test data: %v", err)
+	}
+
+	var encrypted bytes.Buffer
+	if err := sd.EncryptStream(&encrypted, bytes.NewReader(plaintext)); err != nil {
+		t.Fatalf("unexpected error encrypting stream: %v", err)
+	}
+
+	records := splitStreamRecords(t, sd, encrypted.Bytes())
+	if len(records) < 2 {
+		t.Fatalf("expected at least 2 chunk records, got %d", len(records))
+	}
+
+	truncated := bytes.Join(records[:len(records)-1], nil)
+
+	var decrypted bytes.Buffer
+	err := sd.DecryptStream(&decrypted, bytes.NewReader(truncated))
+	if !errors.Is(err, ErrAuthenticationFailed) {
+		t.Fatalf("got error %v, want %v", err, ErrAuthenticationFailed)
+	}
+}
+
+// Test_aesGCMSecureData_DecryptStream_rejectsStreamTruncatedMidChunk cuts the
+// stream off a few bytes into a chunk record, rather than cleanly on a
+// record boundary, so io.ReadFull surfaces io.ErrUnexpectedEOF partway
+// through a header/length/nonce/sealed read instead of a clean io.EOF.
+func Test_aesGCMSecureData_DecryptStream_rejectsStreamTruncatedMidChunk(t *testing.T) {
+	sd := NewAES256GCM(gcmTestKey).(*aesGCMSecureData)
+
+	var encrypted bytes.Buffer
+	if err := sd.EncryptStream(&encrypted, bytes.NewReader([]byte("hello stream"))); err != nil {
+		t.Fatalf("unexpected error encrypting stream: %v", err)
+	}
+
+	truncated := encrypted.Bytes()[:encrypted.Len()-3]
+
+	var decrypted bytes.Buffer
+	err := sd.DecryptStream(&decrypted, bytes.NewReader(truncated))
+	if !errors.Is(err, ErrAuthenticationFailed) {
+		t.Fatalf("got error %v, want %v", err, ErrAuthenticationFailed)
+	}
+}
+
+func Test_aesGCMSecureData_DecryptStream_rejectsReorderedChunks(t *testing.T) {
+	sd := NewAES256GCM(gcmTestKey).(*aesGCMSecureData)
+
+	plaintext := make([]byte, streamChunkSize*2+123)
+	if _, err := rand.Read(plaintext); err != nil {
+		t.Fatalf("unexpected error generating test data: %v", err)
+	}
+
+	var encrypted bytes.Buffer
+	if err := sd.EncryptStream(&encrypted, bytes.NewReader(plaintext)); err != nil {
+		t.Fatalf("unexpected error encrypting stream: %v", err)
+	}
+
+	records := splitStreamRecords(t, sd, encrypted.Bytes())
+	if len(records) < 2 {
+		t.Fatalf("expected at least 2 chunk records, got %d", len(records))
+	}
+	records[0], records[1] = records[1], records[0]
+
+	var decrypted bytes.Buffer
+	err := sd.DecryptStream(&decrypted, bytes.NewReader(bytes.Join(records, nil)))
+	if !errors.Is(err, ErrAuthenticationFailed) {
+		t.Fatalf("got error %v, want %v", err, ErrAuthenticationFailed)
+	}
+}
+
+// Test_aesGCMSecureData_DecryptStream_rejectsOversizedLengthPrefix guards
+// against a regression where an attacker-controlled length prefix is trusted
+// to size an allocation before the chunk it names has been authenticated: a
+// crafted record declaring a chunk far larger than any EncryptStream-produced
+// one must be rejected immediately, not used to allocate gigabytes of memory
+// on the strength of an unauthenticated 4-byte field.
+func Test_aesGCMSecureData_DecryptStream_rejectsOversizedLengthPrefix(t *testing.T) {
+	sd := NewAES256GCM(gcmTestKey).(*aesGCMSecureData)
+
+	gcm, err := sd.gcm()
+	if err != nil {
+		t.Fatalf("unexpected error building gcm: %v", err)
+	}
+
+	var record []byte
+	record = append(record, make([]byte, streamHeaderSize)...) // index 0, not final
+	var lengthPrefix [4]byte
+	binary.BigEndian.PutUint32(lengthPrefix[:], 0xFFFFFFFF)
+	record = append(record, lengthPrefix[:]...)
+	record = append(record, make([]byte, gcm.NonceSize())...)
+
+	err = sd.DecryptStream(io.Discard, bytes.NewReader(record))
+	if !errors.Is(err, ErrAuthenticationFailed) {
+		t.Fatalf("got error %v, want %v", err, ErrAuthenticationFailed)
+	}
+}
+
+func Test_EncryptStruct_DecryptStruct_streamField(t *testing.T) {
+	sd := NewAES256GCM(gcmTestKey)
+
+	instance := &testStreamStruct{Payload: []byte("large attachment body"), Name: value2}
+
+	if _, err := sd.EncryptStruct(instance); err != nil {
+		t.Fatalf("unexpected error encrypting: %v", err)
+	}
+
+	if bytes.Equal(instance.Payload, []byte("large attachment body")) {
+		t.Fatalf("expected the stream field to be encrypted")
+	}
+	if instance.Name != value2 {
+		t.Fatalf("expected unannotated field to be left untouched")
+	}
+
+	if _, err := sd.DecryptStruct(instance); err != nil {
+		t.Fatalf("unexpected error decrypting: %v", err)
+	}
+
+	if string(instance.Payload) != "large attachment body" {
+		t.Fatalf("got %q, want round-tripped payload", instance.Payload)
+	}
+}
+
+func Test_EncryptStruct_streamField_isUnsupportedByNonStreamingCrypto(t *testing.T) {
+	sd := NewAES128ECB(testKey)
+
+	instance := &testStreamStruct{Payload: []byte("large attachment body")}
+
+	if _, err := sd.EncryptStruct(instance); !errors.Is(err, ErrStreamUnsupported) {
+		t.Fatalf("got error %v, want %v", err, ErrStreamUnsupported)
+	}
+}
+
+var _ io.Writer = (*bytes.Buffer)(nil)
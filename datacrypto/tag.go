@@ -0,0 +1,90 @@
+package datacrypto
+
+import "strings"
+
+// fieldMode enumerates the behaviours a `crypt` struct tag can request for a field.
+type fieldMode int
+
+const (
+	// modeNone means the field carries no crypt tag, or one this package
+	// doesn't recognise; it is left untouched.
+	modeNone fieldMode = iota
+	// modeEncrypt reversibly encrypts the field with Crypto.Encrypt/Decrypt.
+	modeEncrypt
+	// modeDeterministic reversibly encrypts the field using a Crypto that
+	// produces the same ciphertext for the same plaintext every time, so the
+	// value stays searchable/indexable once encrypted.
+	modeDeterministic
+	// modeHash replaces the field with a one-way keyed digest.
+	modeHash
+	// modeBlob gathers the field's value into the struct's blob container
+	// instead of encrypting it in place.
+	modeBlob
+	// modeBlobContainer marks the sibling field that stores the encrypted
+	// bundle of every modeBlob field in the struct.
+	modeBlobContainer
+)
+
+// defaultHashAlgo is used by `crypt:"hash"` when no algorithm option is given.
+const defaultHashAlgo = "sha256"
+
+// cryptTag is the parsed form of a `crypt:"..."` struct tag.
+type cryptTag struct {
+	mode     fieldMode
+	hashAlgo string
+	// keys requests that map keys be transformed the same way as map values,
+	// via the `keys` option on a `crypt:"true"`-family tag applied to a map field.
+	keys bool
+	// stream requests that a []byte field be encrypted in chunks via
+	// StreamCrypto instead of being buffered whole through Encrypt, via the
+	// `stream` option on a `crypt:"true"`-family tag.
+	stream bool
+}
+
+// parseCryptTag parses the value of a `crypt` struct tag. Recognised forms are:
+//
+//	crypt:"true"                reversible encryption (the original, pre-existing form)
+//	crypt:"true,deterministic"  reversible, equal-plaintext-equal-ciphertext encryption
+//	crypt:"true,keys"           on a map field, also transform the map's keys
+//	crypt:"true,stream"         on a []byte field, encrypt in chunks via StreamCrypto
+//	crypt:"hash"                one-way keyed digest, sha256 by default
+//	crypt:"hash,sha256"         one-way keyed digest using the named algorithm
+//	crypt:"blob"                gathered into the struct's blob container
+//	crypt:"blob,container"      the sibling []byte field holding the bundle
+//
+// Anything else, including an absent tag, parses to modeNone.
+func parseCryptTag(tagValue string) cryptTag {
+	parts := strings.Split(tagValue, ",")
+	head, rest := parts[0], parts[1:]
+
+	switch head {
+	case "true":
+		ct := cryptTag{mode: modeEncrypt}
+		for _, opt := range rest {
+			switch opt {
+			case "deterministic":
+				ct.mode = modeDeterministic
+			case "keys":
+				ct.keys = true
+			case "stream":
+				ct.stream = true
+			}
+		}
+		return ct
+	case "hash":
+		algo := defaultHashAlgo
+		if len(rest) > 0 && rest[0] != "" {
+			algo = rest[0]
+		}
+		return cryptTag{mode: modeHash, hashAlgo: algo}
+	case "blob":
+		for _, opt := range rest {
+			if opt == "container" {
+				return cryptTag{mode: modeBlobContainer}
+			}
+		}
+		return cryptTag{mode: modeBlob}
+	default:
+		return cryptTag{mode: modeNone}
+	}
+}
@@ -0,0 +1,163 @@
+package datacrypto
+
+import (
+	"errors"
+	"testing"
+)
+
+type testDeterministicStruct struct {
+	Field1 string `crypt:"true,deterministic"`
+	Field2 string
+}
+
+type testHashStruct struct {
+	Field1 string `crypt:"hash"`
+	Field2 string `crypt:"hash,sha256"`
+	Field3 string
+}
+
+type testBlobStruct struct {
+	Field1    string `crypt:"blob"`
+	Field2    string `crypt:"blob"`
+	Field3    string
+	Container []byte `crypt:"blob,container"`
+}
+
+func Test_parseCryptTag(t *testing.T) {
+	tests := []struct {
+		name string
+		tag  string
+		want cryptTag
+	}{
+		{name: "absent", tag: "", want: cryptTag{mode: modeNone}},
+		{name: "plain encrypt", tag: "true", want: cryptTag{mode: modeEncrypt}},
+		{name: "deterministic", tag: "true,deterministic", want: cryptTag{mode: modeDeterministic}},
+		{name: "hash default algo", tag: "hash", want: cryptTag{mode: modeHash, hashAlgo: "sha256"}},
+		{name: "hash explicit algo", tag: "hash,sha256", want: cryptTag{mode: modeHash, hashAlgo: "sha256"}},
+		{name: "blob", tag: "blob", want: cryptTag{mode: modeBlob}},
+		{name: "blob container", tag: "blob,container", want: cryptTag{mode: modeBlobContainer}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseCryptTag(tt.tag)
+			if got != tt.want {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_aesGCMSecureData_deterministicField_isStableAndRoundtrips(t *testing.T) {
+	sd := NewAES256GCM(gcmTestKey)
+
+	first := &testDeterministicStruct{Field1: value1, Field2: value2}
+	second := &testDeterministicStruct{Field1: value1, Field2: value2}
+
+	if _, err := sd.EncryptStruct(first); err != nil {
+		t.Fatalf("unexpected error encrypting: %v", err)
+	}
+	if _, err := sd.EncryptStruct(second); err != nil {
+		t.Fatalf("unexpected error encrypting: %v", err)
+	}
+
+	if first.Field1 != second.Field1 {
+		t.Fatalf("expected deterministic encryption to produce identical ciphertexts, got %q and %q", first.Field1, second.Field1)
+	}
+
+	if _, err := sd.DecryptStruct(first); err != nil {
+		t.Fatalf("unexpected error decrypting: %v", err)
+	}
+
+	if first.Field1 != value1 {
+		t.Fatalf("got %q, want %q", first.Field1, value1)
+	}
+}
+
+func Test_aesECBSecureData_deterministicField_isUnsupported(t *testing.T) {
+	sd := NewAES128ECB(testKey)
+
+	instance := &testDeterministicStruct{Field1: value1}
+
+	if _, err := sd.EncryptStruct(instance); !errors.Is(err, ErrDeterministicUnsupported) {
+		t.Fatalf("got error %v, want %v", err, ErrDeterministicUnsupported)
+	}
+}
+
+func Test_aesGCMSecureData_hashField_isOneWay(t *testing.T) {
+	sd := NewAES256GCM(gcmTestKey)
+
+	instance := &testHashStruct{Field1: value1, Field2: value2, Field3: value3}
+
+	if _, err := sd.EncryptStruct(instance); err != nil {
+		t.Fatalf("unexpected error encrypting: %v", err)
+	}
+
+	if instance.Field1 == value1 || instance.Field2 == value2 {
+		t.Fatalf("expected hashed fields to change, got %+v", instance)
+	}
+	if instance.Field3 != value3 {
+		t.Fatalf("expected unannotated field to be left untouched")
+	}
+
+	hashed := *instance
+
+	if _, err := sd.DecryptStruct(instance); err != nil {
+		t.Fatalf("unexpected error decrypting: %v", err)
+	}
+
+	if *instance != hashed {
+		t.Fatalf("expected hashed fields to be left untouched by DecryptStruct, got %+v want %+v", instance, hashed)
+	}
+}
+
+func Test_hybridSecureData_hashField_isUnsupported(t *testing.T) {
+	priv := mustGenerateTestRSAKey(t)
+	sd := NewHybridRSA(&priv.PublicKey, priv)
+
+	instance := &testHashStruct{Field1: value1}
+
+	if _, err := sd.EncryptStruct(instance); !errors.Is(err, ErrHashUnsupported) {
+		t.Fatalf("got error %v, want %v", err, ErrHashUnsupported)
+	}
+}
+
+func Test_aesGCMSecureData_blobFields_bundleAndRoundtrip(t *testing.T) {
+	sd := NewAES256GCM(gcmTestKey)
+
+	instance := &testBlobStruct{Field1: value1, Field2: value2, Field3: value3}
+
+	if _, err := sd.EncryptStruct(instance); err != nil {
+		t.Fatalf("unexpected error encrypting: %v", err)
+	}
+
+	if instance.Field1 != "" || instance.Field2 != "" {
+		t.Fatalf("expected blob fields to be cleared once bundled, got %+v", instance)
+	}
+	if instance.Field3 != value3 {
+		t.Fatalf("expected unannotated field to be left untouched")
+	}
+	if len(instance.Container) == 0 {
+		t.Fatalf("expected the blob container to hold the encrypted bundle")
+	}
+
+	if _, err := sd.DecryptStruct(instance); err != nil {
+		t.Fatalf("unexpected error decrypting: %v", err)
+	}
+
+	if instance.Field1 != value1 || instance.Field2 != value2 || instance.Field3 != value3 {
+		t.Fatalf("got %+v, want round-tripped values", instance)
+	}
+}
+
+func Test_aesGCMSecureData_blobFields_missingContainerErrors(t *testing.T) {
+	sd := NewAES256GCM(gcmTestKey)
+
+	type noContainer struct {
+		Field1 string `crypt:"blob"`
+	}
+
+	if _, err := sd.EncryptStruct(&noContainer{Field1: value1}); !errors.Is(err, ErrBlobContainerMissing) {
+		t.Fatalf("got error %v, want %v", err, ErrBlobContainerMissing)
+	}
+}